@@ -26,21 +26,37 @@
 //   {{.Date.Year}}, {{.Date.Month}}: Year and month that the page was
 //      published, if file pattern includes it.
 //   {{.URI}}: URI to the page.
+//   {{.Content}}: Rendered HTML body, for pages sourced from Markdown; see Site.Markdown.
 //
 // The following functions are available within templates, in addition
 // to the usual ones:
 //   {{live}}: Whether the page is live, via !appengine.IsDevAppServer().
 //   {{domain}}: When live, the live domain of the page, otherwise empty string.
+//   {{ref "path"}}: Canonical URL of the page at path, absolute when live.
+//   {{relref "path"}}: Root-relative URL of the page at path.
+//
+// {{.Site.Pages}} is also available within each template: a slice of
+// all registered pages, sorted by Date descending.
+//
+// This package predates Go modules and is built GOPATH-style alongside
+// the legacy "appengine" SDK, so it carries no go.mod/go.sum; besides
+// the standard library it depends on github.com/yuin/goldmark,
+// gopkg.in/yaml.v2, github.com/fsnotify/fsnotify,
+// cloud.google.com/go/storage and google.golang.org/api/iterator, which
+// must be vendored alongside it the same way "appengine" is.
 package autosite
 
 import (
+	"bytes"
 	"fmt"
 	"html/template"
 	"log"
 	"net/http"
 	"path/filepath"
+	"runtime"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"appengine"
@@ -52,12 +68,14 @@ var BaseTemplate = "base"
 // New creates a new autosite.
 //
 // New panics on errors reading templates.
-func New(title, glob, liveDomain string, templates []string) Site {
-	s := Site{
+func New(title, glob, liveDomain string, templates []string) *Site {
+	s := &Site{
 		title:      title,
 		liveDomain: liveDomain,
 		glob:       glob,
 		templates:  templates,
+		cache:      newRenderCache(defaultCacheSize),
+		pagesMu:    &sync.RWMutex{},
 	}
 	err := s.read()
 	if err != nil {
@@ -91,20 +109,89 @@ func (s *Site) AddRedirect(uri, redirectURI string) {
 	s.pages[uri] = page{
 		Title:       s.title,
 		URI:         uri,
+		Site:        s,
 		redirectURI: redirectURI,
 	}
 	log.Printf("added redirect on %s to %s\n", uri, redirectURI)
 }
 
-// Register registers the HTTP handlers for the site.
-func (s Site) Register() {
+// CacheSize sets the number of rendered pages kept in the in-memory
+// render cache, dropping any entries already cached.
+func (s *Site) CacheSize(n int) {
+	s.setCache(newRenderCache(n))
+}
+
+// DisableCache turns off the render cache; pages are executed fresh on
+// every request.
+func (s *Site) DisableCache() {
+	s.setCache(nil)
+}
+
+// setCache installs c as the site's render cache and propagates it to
+// every already-parsed page, the same cache object newPage hands out to
+// pages parsed from here on. Without this, CacheSize/DisableCache would
+// only take effect for pages parsed by a future Reload, leaving every
+// page that existed at call time on the old cache.
+func (s *Site) setCache(c *renderCache) {
+	s.cache = c
+	s.pagesMu.Lock()
+	defer s.pagesMu.Unlock()
 	for uri, p := range s.pages {
+		p.cache = c
+		s.pages[uri] = p
+	}
+}
+
+// WithSource configures src as the Source used to read page and
+// template files, in place of the local filesystem, and re-reads pages
+// through it. This is a prerequisite for building a site's content into
+// the binary via go:embed, or serving it out of a Google Cloud Storage
+// bucket without redeploying.
+func (s *Site) WithSource(src Source) {
+	s.src = src
+	if err := s.read(); err != nil {
+		log.Fatalf(err.Error())
+	}
+}
+
+// Register registers the HTTP handlers for the site.
+//
+// Each handler looks up its page in s.pages afresh on every request,
+// under s.pagesMu, rather than closing over a fixed copy; this lets
+// Reload swap in newly parsed pages, e.g. from the dev-mode file
+// watcher, without re-registering handlers.
+func (s *Site) Register() {
+	s.pagesMu.RLock()
+	uris := make([]string, 0, len(s.pages))
+	for uri := range s.pages {
+		uris = append(uris, uri)
+	}
+	s.pagesMu.RUnlock()
+
+	for _, uri := range uris {
+		uri := uri
+		handler := func(w http.ResponseWriter, r *http.Request) {
+			s.pagesMu.RLock()
+			p, ok := s.pages[uri]
+			s.pagesMu.RUnlock()
+			if !ok {
+				http.NotFound(w, r)
+				return
+			}
+			p.ServeHTTP(w, r)
+		}
 		if appengine.IsDevAppServer() {
-			http.Handle(uri, p)
+			http.HandleFunc(uri, handler)
 		} else {
-			http.Handle(fmt.Sprintf("%s%s", s.liveDomain, p.URI), p)
+			http.HandleFunc(fmt.Sprintf("%s%s", s.liveDomain, uri), handler)
+		}
+		log.Printf("registered handler %s\n", uri)
+	}
+
+	if appengine.IsDevAppServer() {
+		if err := s.watch(); err != nil {
+			log.Printf("dev file watcher not started: %v\n", err)
 		}
-		log.Printf("registered handler %s: %+v\n", p.URI, p)
 	}
 }
 
@@ -113,19 +200,31 @@ type Site struct {
 	liveDomain string          // live domain
 	title      string          // title of the site, for HTML <head>
 	glob       string          // file glob for page templates
-	templates  []string        // templates needed for all endpoints
+	templates  []string        // templates needed for all endpoints, if Layouts isn't used
+	layoutsDir string          // layouts directory, if set via Layouts
 	pages      map[string]page // URI -> page mapping
+	cache      *renderCache    // in-memory render cache, nil if disabled via DisableCache
+	pagesMu    *sync.RWMutex   // guards pages against concurrent Reload
+	src        Source          // page/template source, defaulting to the local filesystem
+
+	markdownSources []markdownSource // recorded Markdown calls, replayed by Reload
+	feedSources     []feedSource     // recorded Feed calls, replayed by Reload
 }
 
 // page is a HTML resource.
 type page struct {
-	Title string      // title, for <head>
-	Date  date        // publishing date
-	URI   string      // URI path
-	Data  interface{} // custom data, if any
+	Title   string        // title, for <head>
+	Date    date          // publishing date
+	URI     string        // URI path
+	Data    interface{}   // custom data, if any
+	Content template.HTML // rendered body, for pages sourced from Markdown
+	Site    *Site         // the site the page belongs to, for {{.Site.Pages}}
 
 	redirectURI string             // URI to redirect to
 	tmpl        *template.Template // backing template
+	raw         []byte             // pre-rendered body, for synthesized pages such as feeds
+	contentType string             // Content-Type to serve raw with
+	cache       *renderCache       // shared render cache, nil if disabled
 }
 
 type year int
@@ -161,13 +260,35 @@ func (p page) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		http.Redirect(w, r, p.redirectURI, http.StatusFound)
 		return
 	}
+	if p.raw != nil {
+		w.Header().Set("Content-Type", p.contentType)
+		w.Write(p.raw)
+		return
+	}
 
-	err := p.tmpl.ExecuteTemplate(w, BaseTemplate, p)
-	if err != nil {
+	if p.cache != nil {
+		if body, etag, ok := p.cache.get(p.URI); ok {
+			if r.Header.Get("If-None-Match") == etag {
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+			w.Header().Set("ETag", etag)
+			w.Write(body)
+			return
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := p.tmpl.ExecuteTemplate(&buf, BaseTemplate, p); err != nil {
 		http.Error(w, "Internal server error.", http.StatusInternalServerError)
 		log.Fatal(err.Error())
 		return
 	}
+	body := buf.Bytes()
+	if p.cache != nil {
+		w.Header().Set("ETag", p.cache.put(p.URI, body))
+	}
+	w.Write(body)
 }
 
 // String provides a string representation of the page.
@@ -182,26 +303,137 @@ func (p page) String() string {
 	return r
 }
 
-// read reads pages to serve on the autosite from disk
+// read reads pages to serve on the autosite from disk.
 func (s *Site) read() error {
-	filePaths, err := s.getFiles()
+	pages, err := s.parseAll()
 	if err != nil {
 		return err
 	}
-	s.pages = make(map[string]page)
-	for _, tmplPath := range filePaths {
-		uri, d, err := parsePath(tmplPath)
+	s.pages = pages
+	return nil
+}
+
+// Reload re-parses all pages from disk and atomically swaps them into
+// the site under s.pagesMu, so in-flight requests see either the old or
+// the new set of .tmpl and Markdown pages, never a partial one. It also
+// replays every Markdown and Feed call the site has seen so far against
+// the freshly parsed pages, so content registered outside the .tmpl
+// pipeline survives a reload instead of disappearing until the next
+// restart. Feed pages are rebuilt after the swap, one feed at a time, so
+// a request arriving in between finds no page at a feed's URI.
+// Also clears the render cache, if enabled, since its entries were
+// rendered from the old templates.
+//
+// Reload is normally driven by the dev-mode file watcher started from
+// Register, but can be called directly too.
+func (s *Site) Reload() error {
+	pages, err := s.parseAll()
+	if err != nil {
+		return err
+	}
+	for _, ms := range s.markdownSources {
+		mdPages, err := s.markdownPages(ms.pattern, ms.layout)
 		if err != nil {
 			return err
 		}
-		s.addPage(uri, d, nil, append(s.templates, tmplPath))
+		for uri, p := range mdPages {
+			pages[uri] = p
+		}
+	}
+
+	s.pagesMu.Lock()
+	s.pages = pages
+	s.pagesMu.Unlock()
+
+	for _, fs := range s.feedSources {
+		if err := s.setFeedPage(fs.uri, fs.kind); err != nil {
+			return err
+		}
+	}
+
+	if s.cache != nil {
+		// setCache also hands the fresh cache to the new pages, which
+		// were built holding the old one.
+		s.setCache(newRenderCache(s.cache.size))
 	}
+	log.Printf("reloaded %d pages\n", len(pages))
 	return nil
 }
 
-// getFiles retrieves all pages' file paths from disk.
-func (s Site) getFiles() ([]string, error) {
-	paths, err := filepath.Glob(s.glob)
+// parseAll globs the site's pages and parses their templates across a
+// worker pool bounded by GOMAXPROCS, returning the resulting pages.
+func (s *Site) parseAll() (map[string]page, error) {
+	filePaths, err := s.getFiles()
+	if err != nil {
+		return nil, err
+	}
+
+	type result struct {
+		uri string
+		p   page
+		err error
+	}
+	jobs := make(chan string)
+	results := make(chan result)
+
+	workers := runtime.GOMAXPROCS(0)
+	if workers > len(filePaths) {
+		workers = len(filePaths)
+	}
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go func() {
+			defer wg.Done()
+			for tmplPath := range jobs {
+				uri, d, err := parsePath(tmplPath, ".tmpl")
+				if err != nil {
+					results <- result{err: err}
+					continue
+				}
+				shared, err := s.sharedTemplates(uri)
+				if err != nil {
+					results <- result{err: err}
+					continue
+				}
+				p, err := s.newPage(uri, d, nil, append(shared, tmplPath))
+				if err != nil {
+					results <- result{err: err}
+					continue
+				}
+				results <- result{uri: uri, p: p}
+			}
+		}()
+	}
+	go func() {
+		for _, p := range filePaths {
+			jobs <- p
+		}
+		close(jobs)
+		wg.Wait()
+		close(results)
+	}()
+
+	pages := make(map[string]page)
+	var firstErr error
+	for r := range results {
+		if r.err != nil {
+			if firstErr == nil {
+				firstErr = r.err
+			}
+			continue
+		}
+		pages[r.uri] = r.p
+	}
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	return pages, nil
+}
+
+// getFiles retrieves all pages' file paths from the site's Source.
+func (s *Site) getFiles() ([]string, error) {
+	paths, err := s.source().Glob(s.glob)
 	if err != nil {
 		return []string{}, err
 	}
@@ -222,24 +454,25 @@ func (s Site) getFiles() ([]string, error) {
 	return r[0:i], nil
 }
 
-// parsePath extracts URI and date from a template file path.
-func parsePath(p string) (uri string, d date, err error) {
+// parsePath extracts URI and date from a source file path with the
+// given extension (e.g. ".tmpl" or ".md").
+func parsePath(p, ext string) (uri string, d date, err error) {
 	parts := strings.Split(p, "/")
 	if len(parts) == 2 {
-		// Assumes [dir]/*.tmpl; i.e. no date.
-		uri = fmt.Sprintf("/%s", strings.TrimSuffix(parts[1], ".tmpl"))
+		// Assumes [dir]/*.ext; i.e. no date.
+		uri = fmt.Sprintf("/%s", strings.TrimSuffix(parts[1], ext))
 	} else if len(parts) == 4 {
-		// Assumes [dir]/[yyyy]/[mm]/*.tmpl; i.e. date is present.
+		// Assumes [dir]/[yyyy]/[mm]/*.ext; i.e. date is present.
 		uri = "/" + strings.Join([]string{
 			parts[1],
 			parts[2],
-			strings.TrimSuffix(parts[3], ".tmpl")}, "/")
+			strings.TrimSuffix(parts[3], ext)}, "/")
 		d, err = getDate(parts[1], parts[2])
 		if err != nil {
 			return
 		}
 	} else {
-		err = fmt.Errorf("bad template path: %s", p)
+		err = fmt.Errorf("bad source path: %s", p)
 		return
 	}
 	return uri, d, nil
@@ -262,7 +495,7 @@ func getDate(y, m string) (date, error) {
 }
 
 // getFuncs constructs a map for the extra template functions.
-func (s Site) getFuncs() template.FuncMap {
+func (s *Site) getFuncs() template.FuncMap {
 	isLive := func() bool {
 		return !appengine.IsDevAppServer()
 	}
@@ -274,20 +507,50 @@ func (s Site) getFuncs() template.FuncMap {
 			}
 			return ""
 		},
+		"ref":    s.ref,
+		"relref": s.relref,
 	}
 }
 
-// addPage adds a page to the autosite.
-func (s *Site) addPage(uri string, d date, data interface{}, tmpls []string) {
+// newPage builds a page value for uri.
+func (s *Site) newPage(uri string, d date, data interface{}, tmpls []string) (page, error) {
 	var t *template.Template
 	if len(tmpls) > 0 {
-		t = template.Must(template.New(BaseTemplate).Funcs(s.getFuncs()).ParseFiles(tmpls...))
+		var err error
+		t, err = s.parseTemplate(tmpls)
+		if err != nil {
+			return page{}, err
+		}
 	}
-	s.pages[uri] = page{
+	return page{
 		Title: s.title,
 		URI:   uri,
 		Data:  data,
 		Date:  d,
 		tmpl:  t,
+		cache: s.cache,
+		Site:  s,
+	}, nil
+}
+
+// parseTemplate builds the template set used to render a page, with the
+// autosite function map registered under BaseTemplate. It returns an
+// error, rather than panicking, on a read or parse failure, since it
+// runs from parseAll's worker goroutines during Reload - a template
+// saved mid-edit must not take down the dev server.
+func (s *Site) parseTemplate(tmpls []string) (*template.Template, error) {
+	t := template.New(BaseTemplate).Funcs(s.getFuncs())
+	src := s.source()
+	for _, name := range tmpls {
+		body, err := readAll(src, name)
+		if err != nil {
+			return nil, fmt.Errorf("reading template %s: %v", name, err)
+		}
+		parsed, err := t.New(filepath.Base(name)).Parse(string(body))
+		if err != nil {
+			return nil, fmt.Errorf("parsing template %s: %v", name, err)
+		}
+		t = parsed
 	}
+	return t, nil
 }
@@ -0,0 +1,14 @@
+package autosite
+
+import "testing"
+
+// TestParseTemplateMissingFile checks that a missing template file is
+// reported as an error rather than killing the process, since
+// parseTemplate now runs from Reload's worker goroutines and must let a
+// mid-edit save survive.
+func TestParseTemplateMissingFile(t *testing.T) {
+	s := &Site{}
+	if _, err := s.parseTemplate([]string{"does/not/exist.tmpl"}); err == nil {
+		t.Error("parseTemplate with a missing file: got nil error, want non-nil")
+	}
+}
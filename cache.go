@@ -0,0 +1,85 @@
+package autosite
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"fmt"
+	"sync"
+)
+
+// defaultCacheSize is the number of rendered pages kept in memory per
+// site by default.
+const defaultCacheSize = 128
+
+// renderCache is a small LRU cache of rendered page bodies, keyed by
+// URI, used to avoid re-executing templates on every request and to
+// support conditional GETs via ETag.
+type renderCache struct {
+	mu      sync.Mutex
+	size    int
+	order   *list.List
+	entries map[string]*list.Element
+}
+
+// cacheEntry is one cached, rendered page.
+type cacheEntry struct {
+	uri  string
+	body []byte
+	etag string
+}
+
+// newRenderCache creates a renderCache holding up to size entries.
+func newRenderCache(size int) *renderCache {
+	return &renderCache{
+		size:    size,
+		order:   list.New(),
+		entries: make(map[string]*list.Element),
+	}
+}
+
+// get returns the cached body and ETag for uri, if present.
+func (c *renderCache) get(uri string) (body []byte, etag string, ok bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, found := c.entries[uri]
+	if !found {
+		return nil, "", false
+	}
+	c.order.MoveToFront(el)
+	e := el.Value.(*cacheEntry)
+	return e.body, e.etag, true
+}
+
+// put stores body for uri, evicting the least recently used entry if
+// the cache is full, and returns the computed ETag.
+func (c *renderCache) put(uri string, body []byte) string {
+	etag := fmt.Sprintf(`"%x"`, sha256.Sum256(body))
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, found := c.entries[uri]; found {
+		el.Value.(*cacheEntry).body = body
+		el.Value.(*cacheEntry).etag = etag
+		c.order.MoveToFront(el)
+		return etag
+	}
+	el := c.order.PushFront(&cacheEntry{uri: uri, body: body, etag: etag})
+	c.entries[uri] = el
+	if c.order.Len() > c.size {
+		oldest := c.order.Back()
+		if oldest != nil {
+			c.order.Remove(oldest)
+			delete(c.entries, oldest.Value.(*cacheEntry).uri)
+		}
+	}
+	return etag
+}
+
+// purge drops uri from the cache, if present.
+func (c *renderCache) purge(uri string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, found := c.entries[uri]; found {
+		c.order.Remove(el)
+		delete(c.entries, uri)
+	}
+}
@@ -0,0 +1,29 @@
+package autosite
+
+import (
+	"sync"
+	"testing"
+)
+
+// TestCacheSizePropagatesToExistingPages checks that CacheSize and
+// DisableCache take effect for pages parsed before they're called, not
+// just for pages parsed by a later Reload.
+func TestCacheSizePropagatesToExistingPages(t *testing.T) {
+	s := &Site{
+		cache:   newRenderCache(defaultCacheSize),
+		pagesMu: &sync.RWMutex{},
+	}
+	s.pages = map[string]page{
+		"/hello": {URI: "/hello", cache: s.cache},
+	}
+
+	s.DisableCache()
+	if got := s.pages["/hello"].cache; got != nil {
+		t.Errorf("after DisableCache, page cache = %v, want nil", got)
+	}
+
+	s.CacheSize(4)
+	if got := s.pages["/hello"].cache; got != s.cache {
+		t.Errorf("after CacheSize, page cache = %v, want %v", got, s.cache)
+	}
+}
@@ -0,0 +1,215 @@
+package autosite
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// feedEntry is one page rendered into a feed.
+type feedEntry struct {
+	Title   string
+	URL     string
+	Date    date
+	Summary string
+}
+
+// feedSource records a Feed call's uri and kind, so Reload can rebuild
+// it against a freshly parsed page set - otherwise a dev-mode reload
+// would discard every feed Feed registered.
+type feedSource struct {
+	uri  string
+	kind string
+}
+
+// Feed registers a synthesized handler at uri serving a feed, in kind
+// format ("atom", "rss" or "gemini"), built from all pages with a
+// non-zero Date, sorted most recent first.
+//
+// Feed must be called after the pages it should cover have been added;
+// it snapshots s.pages at call time, the same way ChangeURI and
+// AddRedirect operate on an already-populated site.
+func (s *Site) Feed(uri, kind string) error {
+	if s.pagesMu == nil {
+		s.pagesMu = &sync.RWMutex{}
+	}
+	if err := s.setFeedPage(uri, kind); err != nil {
+		return err
+	}
+	s.pagesMu.Lock()
+	s.feedSources = append(s.feedSources, feedSource{uri, kind})
+	s.pagesMu.Unlock()
+	return nil
+}
+
+// setFeedPage builds the feed for uri/kind from the site's current
+// pages and installs it, without recording uri/kind for replay on
+// Reload; used by Feed, and by Reload itself to rebuild already-
+// registered feeds once their source pages have been refreshed.
+func (s *Site) setFeedPage(uri, kind string) error {
+	entries := s.feedEntries()
+
+	var body []byte
+	var contentType string
+	var err error
+	switch kind {
+	case "atom":
+		contentType = "application/atom+xml"
+		body, err = renderAtom(s.title, s.liveURL(uri), entries)
+	case "rss":
+		contentType = "application/rss+xml"
+		body, err = renderRSS(s.title, s.liveURL(uri), entries)
+	case "gemini":
+		contentType = "text/gemini"
+		body, err = renderGemini(entries)
+	default:
+		return fmt.Errorf("unsupported feed kind: %s", kind)
+	}
+	if err != nil {
+		return err
+	}
+
+	s.pagesMu.Lock()
+	defer s.pagesMu.Unlock()
+	if s.pages == nil {
+		s.pages = make(map[string]page)
+	}
+	s.pages[uri] = page{
+		Title:       s.title,
+		URI:         uri,
+		Site:        s,
+		raw:         body,
+		contentType: contentType,
+	}
+	return nil
+}
+
+// feedEntries returns all pages with a non-zero Date, sorted by Date
+// descending, using the existing date.before comparator.
+func (s Site) feedEntries() []feedEntry {
+	s.pagesMu.RLock()
+	defer s.pagesMu.RUnlock()
+	entries := make([]feedEntry, 0, len(s.pages))
+	for _, p := range s.pages {
+		if p.Date == (date{}) {
+			continue
+		}
+		entries = append(entries, feedEntry{
+			Title:   p.Title,
+			URL:     s.liveURL(p.URI),
+			Date:    p.Date,
+			Summary: summaryOf(p.Data),
+		})
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[j].Date.before(entries[i].Date)
+	})
+	return entries
+}
+
+// summaryOf extracts an optional "summary" field from page Data, as set
+// by Markdown front matter.
+func summaryOf(data interface{}) string {
+	m, ok := data.(map[string]interface{})
+	if !ok {
+		return ""
+	}
+	summary, _ := m["summary"].(string)
+	return summary
+}
+
+// ymd renders d as "YYYY-MM-DD", always on the first of the month since
+// date carries no day.
+func (d date) ymd() string {
+	return fmt.Sprintf("%04d-%02d-01", d.Year, d.Month)
+}
+
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	Title   string      `xml:"title"`
+	ID      string      `xml:"id"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomEntry struct {
+	Title   string   `xml:"title"`
+	ID      string   `xml:"id"`
+	Link    atomLink `xml:"link"`
+	Updated string   `xml:"updated"`
+	Summary string   `xml:"summary,omitempty"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+}
+
+// renderAtom builds an Atom feed from entries.
+func renderAtom(title, selfURL string, entries []feedEntry) ([]byte, error) {
+	f := atomFeed{Title: title, ID: selfURL}
+	for _, e := range entries {
+		f.Entries = append(f.Entries, atomEntry{
+			Title:   e.Title,
+			ID:      e.URL,
+			Link:    atomLink{Href: e.URL},
+			Updated: e.Date.ymd() + "T00:00:00Z",
+			Summary: e.Summary,
+		})
+	}
+	out, err := xml.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), out...), nil
+}
+
+type rssFeed struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title string    `xml:"title"`
+	Link  string    `xml:"link"`
+	Items []rssItem `xml:"item"`
+}
+
+type rssItem struct {
+	Title       string `xml:"title"`
+	Link        string `xml:"link"`
+	PubDate     string `xml:"pubDate"`
+	Description string `xml:"description,omitempty"`
+}
+
+// renderRSS builds an RSS 2.0 feed from entries.
+func renderRSS(title, selfURL string, entries []feedEntry) ([]byte, error) {
+	f := rssFeed{
+		Version: "2.0",
+		Channel: rssChannel{Title: title, Link: selfURL},
+	}
+	for _, e := range entries {
+		f.Channel.Items = append(f.Channel.Items, rssItem{
+			Title:       e.Title,
+			Link:        e.URL,
+			PubDate:     e.Date.ymd(),
+			Description: e.Summary,
+		})
+	}
+	out, err := xml.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), out...), nil
+}
+
+// renderGemini builds a Gemini feed document, one "=> URL YYYY-MM-DD
+// Title" link line per entry, per the Gemini feed convention.
+func renderGemini(entries []feedEntry) ([]byte, error) {
+	var buf bytes.Buffer
+	for _, e := range entries {
+		fmt.Fprintf(&buf, "=> %s %s %s\n", e.URL, e.Date.ymd(), e.Title)
+	}
+	return buf.Bytes(), nil
+}
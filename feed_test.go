@@ -0,0 +1,29 @@
+package autosite
+
+import (
+	"sync"
+	"testing"
+)
+
+// TestFeedEntriesURL checks that feed entry URLs go through liveURL, the
+// same absolute-URL-with-scheme convention ref uses, rather than
+// concatenating the live domain directly.
+func TestFeedEntriesURL(t *testing.T) {
+	s := Site{
+		liveDomain: "example.com",
+		pagesMu:    &sync.RWMutex{},
+		pages: map[string]page{
+			"/2024/03/hello": {URI: "/2024/03/hello", Date: date{Year: 2024, Month: 3}},
+		},
+	}
+	entries := s.feedEntries()
+	if len(entries) != 1 {
+		t.Fatalf("got %d entries, want 1", len(entries))
+	}
+	// In a non-dev-server test binary, appengine.IsDevAppServer() is false,
+	// so the URL should be absolute and carry a scheme.
+	want := "https://example.com/2024/03/hello"
+	if got := entries[0].URL; got != want {
+		t.Errorf("entry URL = %q, want %q", got, want)
+	}
+}
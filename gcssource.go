@@ -0,0 +1,79 @@
+package autosite
+
+import (
+	"context"
+	"io"
+	"path"
+	"strings"
+
+	"cloud.google.com/go/storage"
+	"google.golang.org/api/iterator"
+)
+
+// GCSSource adapts a Google Cloud Storage bucket to Source, letting a
+// site's pages and templates be edited in GCS without redeploying, as
+// is natural for an app running on App Engine.
+type GCSSource struct {
+	Bucket *storage.BucketHandle
+}
+
+// NewGCSSource opens bucketName for reading, using the application's
+// default credentials.
+func NewGCSSource(bucketName string) (GCSSource, error) {
+	client, err := storage.NewClient(context.Background())
+	if err != nil {
+		return GCSSource{}, err
+	}
+	return GCSSource{Bucket: client.Bucket(bucketName)}, nil
+}
+
+// Glob returns the names of all objects in the bucket matching pattern,
+// per path.Match's syntax.
+func (g GCSSource) Glob(pattern string) ([]string, error) {
+	ctx := context.Background()
+	it := g.Bucket.Objects(ctx, &storage.Query{Prefix: globPrefix(pattern)})
+	var names []string
+	for {
+		obj, err := it.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		ok, err := path.Match(pattern, obj.Name)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			names = append(names, obj.Name)
+		}
+	}
+	return names, nil
+}
+
+// Open opens the named object for reading.
+func (g GCSSource) Open(name string) (io.ReadCloser, error) {
+	return g.Bucket.Object(name).NewReader(context.Background())
+}
+
+// Exists reports whether name is present in the bucket.
+func (g GCSSource) Exists(name string) (bool, error) {
+	_, err := g.Bucket.Object(name).Attrs(context.Background())
+	if err == storage.ErrObjectNotExist {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// globPrefix returns the longest literal prefix of pattern, before any
+// glob metacharacter, to scope the GCS object listing.
+func globPrefix(pattern string) string {
+	if i := strings.IndexAny(pattern, "*?["); i != -1 {
+		return pattern[:i]
+	}
+	return pattern
+}
@@ -0,0 +1,97 @@
+package autosite
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// Layouts configures dir as the site's layouts directory, enabling a
+// Hugo-style template cascade in place of the flat list of shared
+// templates passed to New: a page at e.g. /blog/2024/03/hello resolves
+// layouts/blog/single.tmpl, falling back to layouts/_default/single.tmpl,
+// and likewise layouts/blog/baseof.tmpl falling back to
+// layouts/_default/baseof.tmpl. This lets sections such as blog posts
+// and static pages share a site while using distinct chrome. Sites that
+// only ever populate layouts/_default keep working unchanged.
+func (s *Site) Layouts(dir string) {
+	s.layoutsDir = dir
+}
+
+// sharedTemplates returns the templates shared by every page at uri:
+// the flat s.templates list, or, once Layouts has configured a layouts
+// directory, the baseof/single cascade for uri's section.
+func (s Site) sharedTemplates(uri string) ([]string, error) {
+	if s.layoutsDir == "" {
+		return append([]string{}, s.templates...), nil
+	}
+	return s.layoutFiles(section(uri))
+}
+
+// baseTemplates returns the templates wrapping every page at uri: the
+// flat s.templates list, or, once Layouts has configured a layouts
+// directory, just the baseof.tmpl half of the cascade for uri's
+// section. Unlike sharedTemplates, it doesn't require a single.tmpl to
+// exist - callers such as Markdown supply their own content template
+// via their layout argument, and have no use for one.
+func (s Site) baseTemplates(uri string) ([]string, error) {
+	if s.layoutsDir == "" {
+		return append([]string{}, s.templates...), nil
+	}
+	baseof, err := s.resolveLayout(section(uri), "baseof.tmpl")
+	if err != nil {
+		return nil, err
+	}
+	return []string{baseof}, nil
+}
+
+// layoutFiles resolves baseof.tmpl and single.tmpl for section via the
+// _default fallback cascade.
+func (s Site) layoutFiles(sect string) ([]string, error) {
+	baseof, err := s.resolveLayout(sect, "baseof.tmpl")
+	if err != nil {
+		return nil, err
+	}
+	single, err := s.resolveLayout(sect, "single.tmpl")
+	if err != nil {
+		return nil, err
+	}
+	return []string{baseof, single}, nil
+}
+
+// resolveLayout finds layouts/<sect>/<name>, falling back to
+// layouts/_default/<name> when sect doesn't have its own. Existence is
+// checked through the site's Source, not the local filesystem directly,
+// so the cascade also works when layouts are served from an EmbedSource
+// or GCSSource.
+func (s Site) resolveLayout(sect, name string) (string, error) {
+	var candidates []string
+	if sect != "" {
+		candidates = append(candidates, filepath.Join(s.layoutsDir, sect, name))
+	}
+	candidates = append(candidates, filepath.Join(s.layoutsDir, "_default", name))
+	for _, c := range candidates {
+		ok, err := s.source().Exists(c)
+		if err != nil {
+			return "", err
+		}
+		if ok {
+			return c, nil
+		}
+	}
+	return "", fmt.Errorf("no %s found for section %q in %s", name, sect, s.layoutsDir)
+}
+
+// section returns the first path segment of uri, or "" if uri has only
+// one segment. A single-segment URI is a flat, non-dated page (e.g.
+// /blog), and its own name must not be mistaken for a section shared
+// with dated pages nested under a directory of the same name (e.g.
+// /blog/2024/03/hello) - otherwise the flat page picks up that
+// section's layout instead of falling back to _default.
+func section(uri string) string {
+	parts := strings.SplitN(strings.TrimPrefix(uri, "/"), "/", 2)
+	if len(parts) < 2 || parts[0] == "" {
+		return ""
+	}
+	return parts[0]
+}
@@ -0,0 +1,20 @@
+package autosite
+
+import "testing"
+
+func TestSection(t *testing.T) {
+	cases := []struct {
+		uri  string
+		want string
+	}{
+		{"/blog", ""},
+		{"/", ""},
+		{"/2024/03/hello", "2024"},
+		{"/blog/2024/03/hello", "blog"},
+	}
+	for _, c := range cases {
+		if got := section(c.uri); got != c.want {
+			t.Errorf("section(%q) = %q, want %q", c.uri, got, c.want)
+		}
+	}
+}
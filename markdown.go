@@ -0,0 +1,184 @@
+package autosite
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/yuin/goldmark"
+	"gopkg.in/yaml.v2"
+)
+
+// frontMatter is the YAML metadata block at the top of a Markdown page.
+type frontMatter struct {
+	Title string                 `yaml:"title"`
+	Date  string                 `yaml:"date"`
+	URI   string                 `yaml:"uri"`
+	Data  map[string]interface{} `yaml:",inline"`
+}
+
+// frontMatterDelim delimits a front matter block from the Markdown body.
+const frontMatterDelim = "---"
+
+// markdownSource records a Markdown call's pattern and layout, so
+// Reload can replay it against a freshly parsed page set - otherwise a
+// dev-mode reload would discard every page Markdown registered.
+type markdownSource struct {
+	pattern string
+	layout  string
+}
+
+// Markdown registers pages found via pattern (e.g. "pages/*/*/*.md") as
+// Markdown sources, rendering each through layout.
+//
+// Each file may begin with a YAML front matter block, delimited by
+// "---" lines, setting Title, Date (as "2006-01-02") and URI; any other
+// keys populate the page's Data as a map[string]interface{}. The
+// Markdown body is rendered to HTML and made available to layout as
+// {{.Content}}. Markdown returns an error, rather than panicking, if
+// any page fails to be read or parsed.
+func (s *Site) Markdown(pattern, layout string) error {
+	pages, err := s.markdownPages(pattern, layout)
+	if err != nil {
+		return err
+	}
+
+	if s.pagesMu == nil {
+		s.pagesMu = &sync.RWMutex{}
+	}
+	s.pagesMu.Lock()
+	defer s.pagesMu.Unlock()
+	if s.pages == nil {
+		s.pages = make(map[string]page)
+	}
+	for uri, p := range pages {
+		s.pages[uri] = p
+	}
+	s.markdownSources = append(s.markdownSources, markdownSource{pattern, layout})
+	return nil
+}
+
+// markdownPages globs pattern and renders each matching file through
+// layout, returning the resulting pages without registering them on the
+// site. Used by Markdown, and by Reload to replay a recorded
+// markdownSource against a freshly parsed page set.
+func (s *Site) markdownPages(pattern, layout string) (map[string]page, error) {
+	paths, err := s.source().Glob(pattern)
+	if err != nil {
+		return nil, err
+	}
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("no markdown pages found for %s", pattern)
+	}
+	pages := make(map[string]page)
+	for _, p := range paths {
+		if strings.Contains(p, ".#") {
+			continue
+		}
+		uri, pg, err := s.parseMarkdownPage(p, layout)
+		if err != nil {
+			return nil, err
+		}
+		pages[uri] = pg
+	}
+	return pages, nil
+}
+
+// parseMarkdownPage parses and renders a single Markdown page, without
+// registering it on the site.
+//
+// It resolves only the baseof.tmpl half of the Layouts cascade, via
+// baseTemplates rather than sharedTemplates: layout already supplies
+// the page's content template, so a section that configures Layouts
+// purely for shared chrome isn't also forced to provide a single.tmpl
+// it has no use for.
+func (s *Site) parseMarkdownPage(path, layout string) (string, page, error) {
+	raw, err := readAll(s.source(), path)
+	if err != nil {
+		return "", page{}, err
+	}
+	fm, body, err := parseFrontMatter(raw)
+	if err != nil {
+		return "", page{}, fmt.Errorf("parsing front matter in %s: %v", path, err)
+	}
+
+	var buf bytes.Buffer
+	if err := goldmark.Convert(body, &buf); err != nil {
+		return "", page{}, fmt.Errorf("rendering markdown in %s: %v", path, err)
+	}
+
+	uri := fm.URI
+	d := date{}
+	if uri == "" {
+		uri, d, err = parsePath(path, filepath.Ext(path))
+		if err != nil {
+			return "", page{}, err
+		}
+	}
+	if fm.Date != "" {
+		d, err = parseFrontMatterDate(fm.Date)
+		if err != nil {
+			return "", page{}, fmt.Errorf("bad date in %s: %v", path, err)
+		}
+	}
+
+	title := fm.Title
+	if title == "" {
+		title = s.title
+	}
+
+	base, err := s.baseTemplates(uri)
+	if err != nil {
+		return "", page{}, err
+	}
+	tmpl, err := s.parseTemplate(append(base, layout))
+	if err != nil {
+		return "", page{}, fmt.Errorf("parsing templates for %s: %v", path, err)
+	}
+
+	return uri, page{
+		Title:   title,
+		URI:     uri,
+		Date:    d,
+		Data:    fm.Data,
+		Content: template.HTML(buf.String()),
+		Site:    s,
+		tmpl:    tmpl,
+		cache:   s.cache,
+	}, nil
+}
+
+// parseFrontMatter splits raw into front matter and the remaining
+// Markdown body. If raw doesn't begin with a "---" line, the front
+// matter is empty and raw is the body unchanged.
+func parseFrontMatter(raw []byte) (frontMatter, []byte, error) {
+	var fm frontMatter
+	trimmed := bytes.TrimLeft(raw, "\n")
+	delim := []byte(frontMatterDelim)
+	if !bytes.HasPrefix(trimmed, delim) {
+		return fm, raw, nil
+	}
+	rest := bytes.TrimPrefix(trimmed[len(delim):], []byte("\n"))
+	end := bytes.Index(rest, []byte("\n"+frontMatterDelim))
+	if end == -1 {
+		return fm, nil, fmt.Errorf("no closing %s for front matter", frontMatterDelim)
+	}
+	if err := yaml.Unmarshal(rest[:end], &fm); err != nil {
+		return fm, nil, err
+	}
+	body := bytes.TrimLeft(rest[end+len("\n"+frontMatterDelim):], "\n")
+	return fm, body, nil
+}
+
+// parseFrontMatterDate parses a front matter date of the form "2006-01-02".
+func parseFrontMatterDate(s string) (date, error) {
+	t, err := time.Parse("2006-01-02", s)
+	if err != nil {
+		return date{}, err
+	}
+	return date{Year: year(t.Year()), Month: t.Month()}, nil
+}
@@ -0,0 +1,45 @@
+package autosite
+
+import (
+	"os"
+	"sync"
+	"testing"
+)
+
+// TestMarkdownWithBaseofOnlyLayouts checks that Markdown renders a page
+// when Layouts is configured purely for baseof.tmpl chrome and no
+// single.tmpl exists anywhere in the layouts directory - Markdown
+// supplies its own content template via its layout argument, so the
+// cascade shouldn't require a single.tmpl too.
+func TestMarkdownWithBaseofOnlyLayouts(t *testing.T) {
+	dir := t.TempDir()
+	mustWriteFile(t, dir+"/posts/2024/03/hello.md", "---\ntitle: Hello\n---\nBody text.\n")
+	mustWriteFile(t, dir+"/layouts/_default/baseof.tmpl", `{{define "base"}}{{.Content}}{{end}}`)
+	mustWriteFile(t, dir+"/post.tmpl", `unused`)
+
+	// parsePath derives URIs from relative [dir]/yyyy/mm/file paths, so
+	// glob the posts relative to dir.
+	cwd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(cwd)
+
+	s := &Site{title: "Test", pagesMu: &sync.RWMutex{}}
+	s.Layouts(dir + "/layouts")
+
+	if err := s.Markdown("posts/*/*/*.md", "post.tmpl"); err != nil {
+		t.Fatalf("Markdown: %v", err)
+	}
+
+	p, ok := s.pages["/2024/03/hello"]
+	if !ok {
+		t.Fatalf("page /2024/03/hello not registered")
+	}
+	if p.tmpl == nil {
+		t.Fatal("page has no backing template")
+	}
+}
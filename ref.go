@@ -0,0 +1,80 @@
+package autosite
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"appengine"
+)
+
+// Pages returns all pages currently registered on the site, sorted by
+// Date descending, for use from templates as {{.Site.Pages}} to build
+// index or archive pages without duplicating listing logic.
+func (s *Site) Pages() []page {
+	s.pagesMu.RLock()
+	defer s.pagesMu.RUnlock()
+	pages := make([]page, 0, len(s.pages))
+	for _, p := range s.pages {
+		pages = append(pages, p)
+	}
+	sort.Slice(pages, func(i, j int) bool {
+		return pages[j].Date.before(pages[i].Date)
+	})
+	return pages
+}
+
+// ref resolves src - a source-relative path (e.g. "2024/03/hello.md")
+// or a page's URI - to its canonical URL: an absolute URL under
+// liveDomain when the site is live, or a root-relative path otherwise.
+// It returns a template error, rather than panicking, if src doesn't
+// resolve to a known page.
+func (s *Site) ref(src string) (string, error) {
+	p, err := s.lookup(src)
+	if err != nil {
+		return "", err
+	}
+	return s.liveURL(p.URI), nil
+}
+
+// liveURL resolves uri to an absolute URL under liveDomain when the
+// site is live, or returns it unchanged otherwise. It's shared by ref
+// and by Feed, so entry and self URLs use the same scheme convention.
+func (s Site) liveURL(uri string) string {
+	if !appengine.IsDevAppServer() {
+		return "https://" + s.liveDomain + uri
+	}
+	return uri
+}
+
+// relref resolves src like ref, but always returns the root-relative path.
+func (s *Site) relref(src string) (string, error) {
+	p, err := s.lookup(src)
+	if err != nil {
+		return "", err
+	}
+	return p.URI, nil
+}
+
+// lookup finds the page referenced by src, which may be a page's URI
+// (e.g. "/2024/03/hello") or the source-relative path it was parsed
+// from (e.g. "2024/03/hello.md").
+func (s *Site) lookup(src string) (page, error) {
+	key := normalizeRef(src)
+	s.pagesMu.RLock()
+	defer s.pagesMu.RUnlock()
+	for uri, p := range s.pages {
+		if normalizeRef(uri) == key {
+			return p, nil
+		}
+	}
+	return page{}, fmt.Errorf("ref: no page found for %q", src)
+}
+
+// normalizeRef strips any leading slash and extension from ref, so a
+// page's URI and the source path it was parsed from compare equal.
+func normalizeRef(ref string) string {
+	r := strings.TrimPrefix(ref, "/")
+	return strings.TrimSuffix(r, filepath.Ext(r))
+}
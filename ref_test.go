@@ -0,0 +1,73 @@
+package autosite
+
+import (
+	"sync"
+	"testing"
+)
+
+func testSiteWithPage() *Site {
+	return &Site{
+		liveDomain: "example.com",
+		pagesMu:    &sync.RWMutex{},
+		pages: map[string]page{
+			"/2024/03/hello": {URI: "/2024/03/hello"},
+		},
+	}
+}
+
+// TestRelrefResolvesSourcePathAndURI checks that relref resolves both a
+// page's Markdown source path and its URI to the same root-relative
+// path, via normalizeRef's matching.
+func TestRelrefResolvesSourcePathAndURI(t *testing.T) {
+	s := testSiteWithPage()
+	for _, src := range []string{"2024/03/hello.md", "/2024/03/hello", "2024/03/hello"} {
+		got, err := s.relref(src)
+		if err != nil {
+			t.Fatalf("relref(%q): %v", src, err)
+		}
+		if got != "/2024/03/hello" {
+			t.Errorf("relref(%q) = %q, want /2024/03/hello", src, got)
+		}
+	}
+}
+
+// TestRefAbsoluteURL checks that ref resolves a source path to an
+// absolute, scheme-carrying URL under liveDomain.
+func TestRefAbsoluteURL(t *testing.T) {
+	s := testSiteWithPage()
+	got, err := s.ref("2024/03/hello.md")
+	if err != nil {
+		t.Fatalf("ref: %v", err)
+	}
+	want := "https://example.com/2024/03/hello"
+	if got != want {
+		t.Errorf("ref = %q, want %q", got, want)
+	}
+}
+
+// TestRefUnresolved checks that ref/relref return an error, rather than
+// panicking, when src doesn't match any registered page.
+func TestRefUnresolved(t *testing.T) {
+	s := testSiteWithPage()
+	if _, err := s.ref("2024/03/missing.md"); err == nil {
+		t.Error("ref on unresolved src: got nil error, want non-nil")
+	}
+	if _, err := s.relref("2024/03/missing.md"); err == nil {
+		t.Error("relref on unresolved src: got nil error, want non-nil")
+	}
+}
+
+// TestPagesSortedByDateDescending checks Site.Pages sorts newest first.
+func TestPagesSortedByDateDescending(t *testing.T) {
+	s := &Site{
+		pagesMu: &sync.RWMutex{},
+		pages: map[string]page{
+			"/a": {URI: "/a", Date: date{Year: 2023, Month: 1}},
+			"/b": {URI: "/b", Date: date{Year: 2024, Month: 6}},
+		},
+	}
+	pages := s.Pages()
+	if len(pages) != 2 || pages[0].URI != "/b" || pages[1].URI != "/a" {
+		t.Errorf("Pages() = %v, want [/b, /a]", pages)
+	}
+}
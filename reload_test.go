@@ -0,0 +1,58 @@
+package autosite
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// mustWriteFile writes content to path, creating parent directories as
+// needed, failing the test on any error.
+func mustWriteFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestReloadPreservesMarkdownAndFeedPages checks that Reload replays
+// recorded Markdown and Feed registrations against the freshly parsed
+// page set, rather than discarding them - Reload previously only
+// re-parsed the .tmpl pipeline and overwrote s.pages wholesale.
+func TestReloadPreservesMarkdownAndFeedPages(t *testing.T) {
+	dir := t.TempDir()
+	mustWriteFile(t, dir+"/pages/hello.tmpl", `{{define "base"}}hello{{end}}`)
+	mustWriteFile(t, dir+"/posts/2024/03/first.md", "---\ntitle: First\ndate: 2024-03-01\n---\nBody.\n")
+	mustWriteFile(t, dir+"/post.tmpl", `{{define "base"}}{{.Content}}{{end}}`)
+
+	cwd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(cwd)
+
+	s := New("Test", "pages/*.tmpl", "example.com", nil)
+	if err := s.Markdown("posts/*/*/*.md", "post.tmpl"); err != nil {
+		t.Fatalf("Markdown: %v", err)
+	}
+	if err := s.Feed("/feed.atom", "atom"); err != nil {
+		t.Fatalf("Feed: %v", err)
+	}
+
+	if err := s.Reload(); err != nil {
+		t.Fatalf("Reload: %v", err)
+	}
+
+	if _, ok := s.pages["/2024/03/first"]; !ok {
+		t.Error("Markdown page missing from s.pages after Reload")
+	}
+	if _, ok := s.pages["/feed.atom"]; !ok {
+		t.Error("Feed page missing from s.pages after Reload")
+	}
+}
@@ -0,0 +1,93 @@
+package autosite
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// Source abstracts file access for page and template sources, so a
+// Site's content can be read from the local filesystem (the default),
+// a binary built with go:embed, or remote storage such as a Google
+// Cloud Storage bucket, without getFiles and template parsing hard-
+// coding filepath.Glob and the local filesystem.
+type Source interface {
+	// Glob returns the names of all files matching pattern.
+	Glob(pattern string) ([]string, error)
+	// Open opens the named file for reading. The caller must close it.
+	Open(name string) (io.ReadCloser, error)
+	// Exists reports whether name is present, for layout fallback
+	// resolution (layouts.go's resolveLayout) that must work the same
+	// way regardless of which Source backs the site.
+	Exists(name string) (bool, error)
+}
+
+// source returns the site's configured Source, defaulting to the local
+// filesystem.
+func (s *Site) source() Source {
+	if s.src == nil {
+		return osSource{}
+	}
+	return s.src
+}
+
+// readAll reads the full contents of name from src.
+func readAll(src Source, name string) ([]byte, error) {
+	f, err := src.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ioutil.ReadAll(f)
+}
+
+// osSource is the default Source, backed by the local filesystem.
+type osSource struct{}
+
+func (osSource) Glob(pattern string) ([]string, error) {
+	return filepath.Glob(pattern)
+}
+
+func (osSource) Open(name string) (io.ReadCloser, error) {
+	return os.Open(name)
+}
+
+func (osSource) Exists(name string) (bool, error) {
+	_, err := os.Stat(name)
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// EmbedSource adapts an embed.FS to Source, for building a site's pages
+// and templates into the binary via go:embed rather than reading them
+// from disk at runtime.
+type EmbedSource struct {
+	FS fs.FS
+}
+
+func (e EmbedSource) Glob(pattern string) ([]string, error) {
+	return fs.Glob(e.FS, pattern)
+}
+
+func (e EmbedSource) Open(name string) (io.ReadCloser, error) {
+	return e.FS.Open(name)
+}
+
+func (e EmbedSource) Exists(name string) (bool, error) {
+	_, err := fs.Stat(e.FS, name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
@@ -0,0 +1,38 @@
+package autosite
+
+import (
+	"embed"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestOsSourceExists(t *testing.T) {
+	dir := t.TempDir()
+	present := filepath.Join(dir, "baseof.tmpl")
+	if err := os.WriteFile(present, []byte("{{.}}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	missing := filepath.Join(dir, "missing.tmpl")
+
+	var src osSource
+	if ok, err := src.Exists(present); err != nil || !ok {
+		t.Errorf("Exists(%q) = %v, %v; want true, nil", present, ok, err)
+	}
+	if ok, err := src.Exists(missing); err != nil || ok {
+		t.Errorf("Exists(%q) = %v, %v; want false, nil", missing, ok, err)
+	}
+}
+
+//go:embed testdata/embedsource
+var embedSourceFixtures embed.FS
+
+func TestEmbedSourceExists(t *testing.T) {
+	src := EmbedSource{FS: embedSourceFixtures}
+	if ok, err := src.Exists("testdata/embedsource/baseof.tmpl"); err != nil || !ok {
+		t.Errorf("Exists(present) = %v, %v; want true, nil", ok, err)
+	}
+	if ok, err := src.Exists("testdata/embedsource/missing.tmpl"); err != nil || ok {
+		t.Errorf("Exists(missing) = %v, %v; want false, nil", ok, err)
+	}
+}
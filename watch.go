@@ -0,0 +1,106 @@
+package autosite
+
+import (
+	"log"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// reloadDebounce coalesces bursts of filesystem events, such as an
+// editor writing a swap file before the real save, into a single Reload.
+const reloadDebounce = 100 * time.Millisecond
+
+// watch starts an fsnotify watcher over the directories returned by
+// watchedDirs, calling Reload on changes; directories matched only by a
+// Markdown pattern or layout are not watched. It's started
+// from Register when running under the dev server, and runs for the
+// lifetime of the process.
+func (s *Site) watch() error {
+	w, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	for dir := range s.watchedDirs() {
+		if err := w.Add(dir); err != nil {
+			log.Printf("not watching %s: %v\n", dir, err)
+			continue
+		}
+		log.Printf("watching %s for changes\n", dir)
+	}
+	go s.watchLoop(w)
+	return nil
+}
+
+// watchLoop debounces fsnotify events into Reload calls until w is closed.
+func (s *Site) watchLoop(w *fsnotify.Watcher) {
+	defer w.Close()
+	var timer *time.Timer
+	for {
+		select {
+		case ev, ok := <-w.Events:
+			if !ok {
+				return
+			}
+			if strings.Contains(ev.Name, ".#") {
+				continue
+			}
+			if timer == nil {
+				timer = time.AfterFunc(reloadDebounce, s.reloadFromWatcher)
+			} else {
+				timer.Reset(reloadDebounce)
+			}
+		case err, ok := <-w.Errors:
+			if !ok {
+				return
+			}
+			log.Printf("watcher error: %v\n", err)
+		}
+	}
+}
+
+// reloadFromWatcher runs Reload in response to a debounced file change.
+func (s *Site) reloadFromWatcher() {
+	if err := s.Reload(); err != nil {
+		log.Printf("reload failed: %v\n", err)
+	}
+}
+
+// watchedDirs returns the distinct directories implied by s.glob,
+// s.templates and s.layoutsDir.
+func (s Site) watchedDirs() map[string]bool {
+	dirs := map[string]bool{}
+	for _, d := range expandGlobDirs(s.glob) {
+		dirs[d] = true
+	}
+	for _, t := range s.templates {
+		dirs[filepath.Dir(t)] = true
+	}
+	if s.layoutsDir != "" {
+		dirs[s.layoutsDir] = true
+		// fsnotify doesn't recurse, and layouts nest a directory per
+		// section plus _default, so watch those explicitly too.
+		matches, _ := filepath.Glob(filepath.Join(s.layoutsDir, "*"))
+		for _, m := range matches {
+			dirs[m] = true
+		}
+	}
+	return dirs
+}
+
+// expandGlobDirs returns the existing directories matched by the
+// directory portion of glob. glob's own directory component can itself
+// be a pattern - e.g. "pages/*/*/*.md" for dated posts has directory
+// "pages/*/*" - which fsnotify can't watch directly, so it needs
+// expanding into concrete paths the same way layoutsDir's section
+// subdirectories are.
+func expandGlobDirs(glob string) []string {
+	dir := filepath.Dir(glob)
+	matches, err := filepath.Glob(dir)
+	if err != nil || len(matches) == 0 {
+		return []string{dir}
+	}
+	return matches
+}
@@ -0,0 +1,33 @@
+package autosite
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestExpandGlobDirsDated checks that the directory portion of a dated
+// glob, which is itself a pattern, expands to the concrete directories
+// fsnotify can watch, rather than the literal (non-existent) pattern.
+func TestExpandGlobDirsDated(t *testing.T) {
+	root := t.TempDir()
+	for _, dir := range []string{"pages/2024/03", "pages/2024/04"} {
+		if err := os.MkdirAll(filepath.Join(root, dir), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	glob := filepath.Join(root, "pages/*/*/*.md")
+	got := expandGlobDirs(glob)
+	want := map[string]bool{
+		filepath.Join(root, "pages/2024/03"): true,
+		filepath.Join(root, "pages/2024/04"): true,
+	}
+	if len(got) != len(want) {
+		t.Fatalf("expandGlobDirs(%q) = %v, want %v", glob, got, want)
+	}
+	for _, d := range got {
+		if !want[d] {
+			t.Errorf("expandGlobDirs(%q) included unexpected dir %q", glob, d)
+		}
+	}
+}